@@ -0,0 +1,116 @@
+package exporter
+
+import (
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/apigateway"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/databasemigrationservice"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// EndpointOverride points one AWS service at a non-default endpoint, for
+// LocalStack, MinIO, or any other AWS-compatible mock. It is keyed by the
+// same short service name used by the serviceRegistry (see
+// service_registry.go), e.g. "cloudwatch", "ec2", "apigateway".
+type EndpointOverride struct {
+	// URL is the base endpoint this service's client talks to, e.g.
+	// http://localhost:4566 for LocalStack.
+	URL string `yaml:"url"`
+	// DisableSSL rewrites URL's scheme to http if it was given as https. Most
+	// mocks don't terminate TLS, so this saves having to hand-edit the URL.
+	DisableSSL bool `yaml:"disable_ssl"`
+	// PathStyle disables virtual-host-style addressing
+	// (bucket.service.region.amazonaws.com) in favor of path-style
+	// (endpoint/bucket), which LocalStack and most S3-compatible mocks
+	// require. It maps directly onto aws.Endpoint.HostnameImmutable.
+	PathStyle bool `yaml:"path_style"`
+	// SigningRegion overrides the region used to sign requests, for mocks
+	// that don't validate SigV4 regions strictly.
+	SigningRegion string `yaml:"signing_region"`
+}
+
+// endpointOverrideServiceIDs maps each service's SDK ServiceID, the string
+// passed into an EndpointResolverWithOptions, back to the short name used to
+// key EndpointOverrides and the per-service AWS_ENDPOINT_URL_* env vars.
+var endpointOverrideServiceIDs = map[string]string{
+	cloudwatch.ServiceID:               "cloudwatch",
+	resourcegroupstaggingapi.ServiceID: "tagging",
+	sts.ServiceID:                      "sts",
+	autoscaling.ServiceID:              "asg",
+	ec2.ServiceID:                      "ec2",
+	databasemigrationservice.ServiceID: "dms",
+	apigateway.ServiceID:               "apigateway",
+	cloudwatchlogs.ServiceID:           "logs",
+	iam.ServiceID:                      "iam",
+}
+
+// endpointOverrideFor returns the override configured for name, checking
+// overrides (ScrapeConf's EndpointOverrides) before the per-service
+// AWS_ENDPOINT_URL_<NAME> env var, e.g. AWS_ENDPOINT_URL_CLOUDWATCH.
+func endpointOverrideFor(overrides map[string]EndpointOverride, name string) (EndpointOverride, bool) {
+	if o, ok := overrides[name]; ok && o.URL != "" {
+		return o, true
+	}
+	if url := os.Getenv("AWS_ENDPOINT_URL_" + strings.ToUpper(name)); url != "" {
+		return EndpointOverride{URL: url}, true
+	}
+	return EndpointOverride{}, false
+}
+
+func buildEndpoint(o EndpointOverride, region string) aws.Endpoint {
+	url := o.URL
+	if o.DisableSSL {
+		url = strings.Replace(url, "https://", "http://", 1)
+	}
+
+	signingRegion := o.SigningRegion
+	if signingRegion == "" {
+		signingRegion = region
+	}
+
+	return aws.Endpoint{
+		URL:               url,
+		SigningRegion:     signingRegion,
+		HostnameImmutable: o.PathStyle,
+		Source:            aws.EndpointSourceCustom,
+	}
+}
+
+// newEndpointResolver builds the EndpointResolverWithOptions shared by every
+// client the session cache constructs. For each request it tries, in order:
+//
+//  1. a per-service override from overrides or its AWS_ENDPOINT_URL_<NAME>
+//     env var
+//  2. the blanket AWS_ENDPOINT_URL env var, which points every service at
+//     the same endpoint and predates per-service overrides
+//  3. the default resolver, which defers to the SDK's normal endpoint
+//     resolution by returning EndpointNotFoundError
+func newEndpointResolver(overrides map[string]EndpointOverride) aws.EndpointResolverWithOptions {
+	globalOverride := os.Getenv("AWS_ENDPOINT_URL")
+
+	return aws.EndpointResolverWithOptionsFunc(func(service, region string, options ...interface{}) (aws.Endpoint, error) {
+		if name, ok := endpointOverrideServiceIDs[service]; ok {
+			if override, ok := endpointOverrideFor(overrides, name); ok {
+				return buildEndpoint(override, region), nil
+			}
+		}
+
+		if globalOverride != "" {
+			return aws.Endpoint{URL: globalOverride, Source: aws.EndpointSourceCustom}, nil
+		}
+
+		return defaultEndpointResolver(service, region, options...)
+	})
+}
+
+func defaultEndpointResolver(service, region string, options ...interface{}) (aws.Endpoint, error) {
+	return aws.Endpoint{}, &aws.EndpointNotFoundError{}
+}
@@ -0,0 +1,95 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestEndpointOverrideFor(t *testing.T) {
+	t.Run("an explicit config override wins over the env var", func(t *testing.T) {
+		t.Setenv("AWS_ENDPOINT_URL_CLOUDWATCH", "http://env-override:4566")
+		overrides := map[string]EndpointOverride{
+			"cloudwatch": {URL: "http://config-override:4566"},
+		}
+
+		got, ok := endpointOverrideFor(overrides, "cloudwatch")
+		if !ok {
+			t.Fatalf("endpointOverrideFor() ok = false, want true")
+		}
+		if got.URL != "http://config-override:4566" {
+			t.Errorf("endpointOverrideFor() URL = %q, want the config override", got.URL)
+		}
+	})
+
+	t.Run("falls back to the per-service env var when unconfigured", func(t *testing.T) {
+		t.Setenv("AWS_ENDPOINT_URL_EC2", "http://env-override:4566")
+
+		got, ok := endpointOverrideFor(nil, "ec2")
+		if !ok {
+			t.Fatalf("endpointOverrideFor() ok = false, want true")
+		}
+		if got.URL != "http://env-override:4566" {
+			t.Errorf("endpointOverrideFor() URL = %q, want the env override", got.URL)
+		}
+	})
+
+	t.Run("a config entry with no URL doesn't shadow the env var", func(t *testing.T) {
+		t.Setenv("AWS_ENDPOINT_URL_IAM", "http://env-override:4566")
+		overrides := map[string]EndpointOverride{
+			"iam": {PathStyle: true},
+		}
+
+		got, ok := endpointOverrideFor(overrides, "iam")
+		if !ok {
+			t.Fatalf("endpointOverrideFor() ok = false, want true")
+		}
+		if got.URL != "http://env-override:4566" {
+			t.Errorf("endpointOverrideFor() URL = %q, want the env override", got.URL)
+		}
+	})
+
+	t.Run("no override configured", func(t *testing.T) {
+		_, ok := endpointOverrideFor(nil, "sts")
+		if ok {
+			t.Errorf("endpointOverrideFor() ok = true, want false")
+		}
+	})
+}
+
+func TestBuildEndpoint(t *testing.T) {
+	t.Run("DisableSSL rewrites an https URL to http", func(t *testing.T) {
+		got := buildEndpoint(EndpointOverride{URL: "https://localhost:4566", DisableSSL: true}, "us-east-1")
+		if got.URL != "http://localhost:4566" {
+			t.Errorf("buildEndpoint() URL = %q, want http scheme", got.URL)
+		}
+	})
+
+	t.Run("SigningRegion defaults to the requested region", func(t *testing.T) {
+		got := buildEndpoint(EndpointOverride{URL: "http://localhost:4566"}, "us-west-2")
+		if got.SigningRegion != "us-west-2" {
+			t.Errorf("buildEndpoint() SigningRegion = %q, want %q", got.SigningRegion, "us-west-2")
+		}
+	})
+
+	t.Run("an explicit SigningRegion overrides the requested region", func(t *testing.T) {
+		got := buildEndpoint(EndpointOverride{URL: "http://localhost:4566", SigningRegion: "us-east-1"}, "us-west-2")
+		if got.SigningRegion != "us-east-1" {
+			t.Errorf("buildEndpoint() SigningRegion = %q, want %q", got.SigningRegion, "us-east-1")
+		}
+	})
+
+	t.Run("PathStyle maps to HostnameImmutable", func(t *testing.T) {
+		got := buildEndpoint(EndpointOverride{URL: "http://localhost:4566", PathStyle: true}, "us-east-1")
+		if !got.HostnameImmutable {
+			t.Errorf("buildEndpoint() HostnameImmutable = false, want true")
+		}
+	})
+
+	t.Run("always returns a custom endpoint source", func(t *testing.T) {
+		got := buildEndpoint(EndpointOverride{URL: "http://localhost:4566"}, "us-east-1")
+		if got.Source != aws.EndpointSourceCustom {
+			t.Errorf("buildEndpoint() Source = %v, want %v", got.Source, aws.EndpointSourceCustom)
+		}
+	})
+}
@@ -0,0 +1,194 @@
+package exporter
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/apigateway"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/databasemigrationservice"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+)
+
+// serviceRegistryEntry describes how to build a client for an AWS service
+// that doesn't have its own Get* accessor and clientCache field (cloudwatch,
+// tagging, and sts are hot-path enough to keep those). Adding a new service
+// here is enough to make it reachable through GetService, and therefore
+// from YAML, without growing clientCache.
+//
+// The v1 SDK needed a per-service FIPS endpoint URL template; v2 exposes
+// UseFIPSEndpoint directly on each service's Options, so each factory below
+// just flips that on instead of carrying a template string.
+type serviceRegistryEntry struct {
+	factory func(s *sessionCache, ctx context.Context, region *string, role Role) any
+}
+
+var serviceRegistry = map[string]serviceRegistryEntry{
+	"asg": {
+		factory: func(s *sessionCache, ctx context.Context, region *string, role Role) any {
+			common := s.commonClientOptions(ctx, "asg", *region, role)
+			return autoscaling.NewFromConfig(s.baseConfig(ctx), func(o *autoscaling.Options) {
+				o.Region = common.region
+				o.Credentials = common.credentials
+				o.Retryer = common.retryer
+				o.HTTPClient = common.httpClient
+
+				if common.debug {
+					o.ClientLogMode = aws.LogRequestWithBody | aws.LogResponseWithBody
+				}
+			})
+		},
+	},
+	"ec2": {
+		factory: func(s *sessionCache, ctx context.Context, region *string, role Role) any {
+			common := s.commonClientOptions(ctx, "ec2", *region, role)
+			return ec2.NewFromConfig(s.baseConfig(ctx), func(o *ec2.Options) {
+				o.Region = common.region
+				o.Credentials = common.credentials
+				o.Retryer = common.retryer
+				o.HTTPClient = common.httpClient
+
+				if common.fips {
+					// https://docs.aws.amazon.com/general/latest/gr/ec2-service.html
+					o.EndpointOptions.UseFIPSEndpoint = aws.FIPSEndpointStateEnabled
+				}
+
+				if common.debug {
+					o.ClientLogMode = aws.LogRequestWithBody | aws.LogResponseWithBody
+				}
+			})
+		},
+	},
+	"dms": {
+		factory: func(s *sessionCache, ctx context.Context, region *string, role Role) any {
+			common := s.commonClientOptions(ctx, "dms", *region, role)
+			return databasemigrationservice.NewFromConfig(s.baseConfig(ctx), func(o *databasemigrationservice.Options) {
+				o.Region = common.region
+				o.Credentials = common.credentials
+				o.Retryer = common.retryer
+				o.HTTPClient = common.httpClient
+
+				if common.fips {
+					// https://docs.aws.amazon.com/general/latest/gr/dms.html
+					o.EndpointOptions.UseFIPSEndpoint = aws.FIPSEndpointStateEnabled
+				}
+
+				if common.debug {
+					o.ClientLogMode = aws.LogRequestWithBody | aws.LogResponseWithBody
+				}
+			})
+		},
+	},
+	"apigateway": {
+		factory: func(s *sessionCache, ctx context.Context, region *string, role Role) any {
+			common := s.commonClientOptions(ctx, "apigateway", *region, role)
+			return apigateway.NewFromConfig(s.baseConfig(ctx), func(o *apigateway.Options) {
+				o.Region = common.region
+				o.Credentials = common.credentials
+				o.Retryer = common.retryer
+				o.HTTPClient = common.httpClient
+
+				if common.fips {
+					// https://docs.aws.amazon.com/general/latest/gr/apigateway.html
+					o.EndpointOptions.UseFIPSEndpoint = aws.FIPSEndpointStateEnabled
+				}
+
+				if common.debug {
+					o.ClientLogMode = aws.LogRequestWithBody | aws.LogResponseWithBody
+				}
+			})
+		},
+	},
+	"logs": {
+		factory: func(s *sessionCache, ctx context.Context, region *string, role Role) any {
+			common := s.commonClientOptions(ctx, "logs", *region, role)
+			return cloudwatchlogs.NewFromConfig(s.baseConfig(ctx), func(o *cloudwatchlogs.Options) {
+				o.Region = common.region
+				o.Credentials = common.credentials
+				o.Retryer = common.retryer
+				o.HTTPClient = common.httpClient
+
+				if common.fips {
+					// https://docs.aws.amazon.com/general/latest/gr/cwl_region.html
+					o.EndpointOptions.UseFIPSEndpoint = aws.FIPSEndpointStateEnabled
+				}
+
+				if common.debug {
+					o.ClientLogMode = aws.LogRequestWithBody | aws.LogResponseWithBody
+				}
+			})
+		},
+	},
+	"iam": {
+		factory: func(s *sessionCache, ctx context.Context, region *string, role Role) any {
+			// IAM is a global service: region only selects which regional
+			// endpoint signs the request, not where it's served from.
+			common := s.commonClientOptions(ctx, "iam", *region, role)
+			return iam.NewFromConfig(s.baseConfig(ctx), func(o *iam.Options) {
+				o.Region = common.region
+				o.Credentials = common.credentials
+				o.Retryer = common.retryer
+				o.HTTPClient = common.httpClient
+
+				if common.fips {
+					// https://docs.aws.amazon.com/general/latest/gr/iam-service.html
+					o.EndpointOptions.UseFIPSEndpoint = aws.FIPSEndpointStateEnabled
+				}
+
+				if common.debug {
+					o.ClientLogMode = aws.LogRequestWithBody | aws.LogResponseWithBody
+				}
+			})
+		},
+	},
+}
+
+// GetService looks up name in serviceRegistry and returns a cached client
+// for region/role, building one through the registered factory on first
+// use. cloudwatch, tagging, and sts are served from their own Get* methods
+// instead, so there's a single cached instance per service rather than two
+// independent caches for the same client. Returns nil if name isn't
+// registered.
+func (s *sessionCache) GetService(ctx context.Context, name string, region *string, role Role) any {
+	switch name {
+	case "cloudwatch":
+		return s.GetCloudwatch(ctx, region, role)
+	case "tagging":
+		return s.GetTagging(ctx, region, role)
+	case "sts":
+		return s.GetSTS(ctx, role)
+	}
+
+	entry, ok := serviceRegistry[name]
+	if !ok {
+		return nil
+	}
+
+	// A (role, region) pair that wasn't enumerated at NewSessionCache time has
+	// no entry in s.clients; rather than panic on the resulting nil cache,
+	// such a pair just gets an uncached client built fresh on every call.
+	cache, ok := s.clients[clientKey{role, *region}]
+	if !ok {
+		return entry.factory(s, ctx, region, role)
+	}
+	if svc, ok := cache.services.Load(name); ok {
+		return svc
+	}
+
+	svc, _ := cache.services.LoadOrStore(name, entry.factory(s, ctx, region, role))
+	return svc
+}
+
+// GetCloudWatchLogs returns a CloudWatch Logs client for region/role, e.g.
+// to discover IncomingBytes per log group.
+func (s *sessionCache) GetCloudWatchLogs(ctx context.Context, region *string, role Role) *cloudwatchlogs.Client {
+	return s.GetService(ctx, "logs", region, role).(*cloudwatchlogs.Client)
+}
+
+// GetIAM returns an IAM client for region/role, e.g. to compute access-key
+// age metrics.
+func (s *sessionCache) GetIAM(ctx context.Context, region *string, role Role) *iam.Client {
+	return s.GetService(ctx, "iam", region, role).(*iam.Client)
+}
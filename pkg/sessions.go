@@ -1,89 +1,107 @@
 package exporter
 
 import (
+	"context"
 	"fmt"
-	"os"
+	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/client"
-	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
-	"github.com/aws/aws-sdk-go/aws/endpoints"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/apigateway"
-	"github.com/aws/aws-sdk-go/service/apigateway/apigatewayiface"
-	"github.com/aws/aws-sdk-go/service/autoscaling"
-	"github.com/aws/aws-sdk-go/service/autoscaling/autoscalingiface"
-	"github.com/aws/aws-sdk-go/service/cloudwatch"
-	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
-	"github.com/aws/aws-sdk-go/service/databasemigrationservice"
-	"github.com/aws/aws-sdk-go/service/databasemigrationservice/databasemigrationserviceiface"
-	"github.com/aws/aws-sdk-go/service/ec2"
-	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
-	r "github.com/aws/aws-sdk-go/service/resourcegroupstaggingapi"
-	"github.com/aws/aws-sdk-go/service/resourcegroupstaggingapi/resourcegroupstaggingapiiface"
-	"github.com/aws/aws-sdk-go/service/sts"
-	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/apigateway"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/databasemigrationservice"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
-// SessionCache is an interface to a cache of sessions and clients for all the
+// SessionCache is an interface to a cache of AWS configs and clients for all the
 // roles specified by the exporter. For jobs with many duplicate roles, this provides
 // relief to the AWS API and prevents timeouts by excessive credential requesting.
+//
+// Every getter takes a context so that a scrape deadline can cancel an
+// in-flight AWS call instead of letting it run past the scrape interval.
 type SessionCache interface {
-	GetSTS(Role) stsiface.STSAPI
-	GetCloudwatch(*string, Role) cloudwatchiface.CloudWatchAPI
-	GetTagging(*string, Role) resourcegroupstaggingapiiface.ResourceGroupsTaggingAPIAPI
-	GetASG(*string, Role) autoscalingiface.AutoScalingAPI
-	GetEC2(*string, Role) ec2iface.EC2API
-	GetDMS(*string, Role) databasemigrationserviceiface.DatabaseMigrationServiceAPI
-	GetAPIGateway(*string, Role) apigatewayiface.APIGatewayAPI
-	Refresh()
+	GetSTS(ctx context.Context, role Role) *sts.Client
+	GetCloudwatch(ctx context.Context, region *string, role Role) *cloudwatch.Client
+	GetTagging(ctx context.Context, region *string, role Role) *resourcegroupstaggingapi.Client
+	GetASG(ctx context.Context, region *string, role Role) *autoscaling.Client
+	GetEC2(ctx context.Context, region *string, role Role) *ec2.Client
+	GetDMS(ctx context.Context, region *string, role Role) *databasemigrationservice.Client
+	GetAPIGateway(ctx context.Context, region *string, role Role) *apigateway.Client
+	GetCloudWatchLogs(ctx context.Context, region *string, role Role) *cloudwatchlogs.Client
+	GetIAM(ctx context.Context, region *string, role Role) *iam.Client
+	// GetService looks up name in the service registry (see
+	// service_registry.go) and returns a cached client for it, so new AWS
+	// services can be wired up from YAML without adding another Get* method
+	// here. Returns nil if name isn't registered.
+	GetService(ctx context.Context, name string, region *string, role Role) any
+	Refresh(ctx context.Context)
 	Clear()
 }
 
 type sessionCache struct {
 	stsRegion        string
-	session          *session.Session
-	endpointResolver endpoints.ResolverFunc
-	stscache         map[Role]stsiface.STSAPI
-	clients          map[Role]map[string]*clientCache
-	cleared          bool
-	refreshed        bool
-	mu               sync.Mutex
+	config           atomic.Pointer[aws.Config]
+	endpointResolver aws.EndpointResolverWithOptions
+	httpClientConfig HTTPClientConfig
+	transport        *http.Transport
+	limiters         *limiterRegistry
+	metrics          *awsMetrics
+	stscache         map[Role]*atomic.Pointer[sts.Client]
+	clients          map[clientKey]*clientCache
 	fips             bool
 	logger           Logger
 }
 
+// clientKey identifies one (role, region) pair's entry in sessionCache.clients.
+type clientKey struct {
+	role   Role
+	region string
+}
+
+// clientCache holds the per-(role, region) clients built from a single
+// sessionCache. Every field is safe for lock-free concurrent access: the
+// GetX methods load first and lazily build on a miss, racing callers settle
+// on whichever client won the CompareAndSwap/LoadOrStore, and Clear resets
+// every field back to its zero value rather than replacing the clientCache
+// itself.
 type clientCache struct {
 	// if we know that this job is only used for static
 	// then we don't have to construct as many cached connections
 	// later on
 	onlyStatic bool
-	cloudwatch cloudwatchiface.CloudWatchAPI
-	tagging    resourcegroupstaggingapiiface.ResourceGroupsTaggingAPIAPI
-	asg        autoscalingiface.AutoScalingAPI
-	ec2        ec2iface.EC2API
-	dms        databasemigrationserviceiface.DatabaseMigrationServiceAPI
-	apiGateway apigatewayiface.APIGatewayAPI
+	cloudwatch atomic.Pointer[cloudwatch.Client]
+	tagging    atomic.Pointer[resourcegroupstaggingapi.Client]
+	// services holds clients built through the serviceRegistry (see
+	// service_registry.go), keyed by registry name. New services added to the
+	// registry don't need a dedicated field here.
+	services sync.Map
 }
 
 // NewSessionCache creates a new session cache to use when fetching data from
-// AWS.
-func NewSessionCache(config ScrapeConf, fips bool, logger Logger) SessionCache {
-	stscache := map[Role]stsiface.STSAPI{}
-	roleCache := map[Role]map[string]*clientCache{}
+// AWS. The returned cache shares a single *http.Transport (and thus a single
+// connection pool) across every service client it builds, and registers its
+// request/retry/throttle metrics against reg.
+func NewSessionCache(config ScrapeConf, fips bool, logger Logger, reg prometheus.Registerer) SessionCache {
+	stscache := map[Role]*atomic.Pointer[sts.Client]{}
+	clients := map[clientKey]*clientCache{}
 
 	for _, discoveryJob := range config.Discovery.Jobs {
 		for _, role := range discoveryJob.Roles {
 			if _, ok := stscache[role]; !ok {
-				stscache[role] = nil
-			}
-			if _, ok := roleCache[role]; !ok {
-				roleCache[role] = map[string]*clientCache{}
+				stscache[role] = &atomic.Pointer[sts.Client]{}
 			}
 			for _, region := range discoveryJob.Regions {
-				roleCache[role][region] = &clientCache{}
+				clients[clientKey{role, region}] = &clientCache{}
 			}
 		}
 	}
@@ -91,17 +109,14 @@ func NewSessionCache(config ScrapeConf, fips bool, logger Logger) SessionCache {
 	for _, staticJob := range config.Static {
 		for _, role := range staticJob.Roles {
 			if _, ok := stscache[role]; !ok {
-				stscache[role] = nil
-			}
-
-			if _, ok := roleCache[role]; !ok {
-				roleCache[role] = map[string]*clientCache{}
+				stscache[role] = &atomic.Pointer[sts.Client]{}
 			}
 
 			for _, region := range staticJob.Regions {
 				// Only write a new region in if the region does not exist
-				if _, ok := roleCache[role][region]; !ok {
-					roleCache[role][region] = &clientCache{
+				key := clientKey{role, region}
+				if _, ok := clients[key]; !ok {
+					clients[key] = &clientCache{
 						onlyStatic: true,
 					}
 				}
@@ -109,344 +124,449 @@ func NewSessionCache(config ScrapeConf, fips bool, logger Logger) SessionCache {
 		}
 	}
 
-	endpointResolver := endpoints.DefaultResolver().EndpointFor
+	endpointResolver := newEndpointResolver(config.EndpointOverrides)
 
-	endpointUrlOverride := os.Getenv("AWS_ENDPOINT_URL")
-	if endpointUrlOverride != "" {
-		// allow override of all endpoints for local testing
-		endpointResolver = func(service, region string, optFns ...func(*endpoints.Options)) (endpoints.ResolvedEndpoint, error) {
-			return endpoints.ResolvedEndpoint{
-				URL: endpointUrlOverride,
-			}, nil
-		}
-	}
+	httpClientConfig := config.HTTPClient.withDefaults()
+	metrics := newAWSMetrics()
+	metrics.MustRegister(reg)
 
 	return &sessionCache{
 		stsRegion:        config.StsRegion,
-		session:          nil,
 		endpointResolver: endpointResolver,
+		httpClientConfig: httpClientConfig,
+		transport:        newHTTPTransport(httpClientConfig),
+		limiters:         newLimiterRegistry(httpClientConfig.RateLimitPerSecond, httpClientConfig.RateLimitBurst),
+		metrics:          metrics,
 		stscache:         stscache,
-		clients:          roleCache,
+		clients:          clients,
 		fips:             fips,
-		cleared:          false,
-		refreshed:        false,
 		logger:           logger,
 	}
 }
 
-// Refresh and Clear help to avoid using lock primitives by asserting that
-// there are no ongoing writes to the map.
-func (s *sessionCache) Clear() {
-	if s.cleared {
-		return
-	}
-
-	for role := range s.stscache {
-		s.stscache[role] = nil
+// httpClientFor builds an *http.Client that shares the cache's connection
+// pool but is rate-limited and instrumented for one specific
+// (service, region, role) triple.
+func (s *sessionCache) httpClientFor(service, region string, role Role) *http.Client {
+	return &http.Client{
+		Transport: &instrumentedTransport{
+			next:    s.transport,
+			limiter: s.limiters.limiterFor(service, region, role),
+			service: service,
+			region:  region,
+			metrics: s.metrics,
+		},
 	}
-
-	for role, regions := range s.clients {
-		for region := range regions {
-			s.clients[role][region].cloudwatch = nil
-			s.clients[role][region].tagging = nil
-			s.clients[role][region].asg = nil
-			s.clients[role][region].ec2 = nil
-			s.clients[role][region].dms = nil
-			s.clients[role][region].apiGateway = nil
-		}
-	}
-	s.cleared = true
-	s.refreshed = false
 }
 
-func (s *sessionCache) Refresh() {
-	// TODO: make all the getter functions atomic pointer loads and sets
-	if s.refreshed {
-		return
-	}
+// retryerFor returns a retryer constructor for service/region whose retries
+// and throttles are counted in s.metrics.
+func (s *sessionCache) retryerFor(service, region string) func() aws.Retryer {
+	return newRetryer(service, region, s.httpClientConfig, s.metrics)
+}
 
-	// sessions really only need to be constructed once at runtime
-	if s.session == nil {
-		s.session = createAWSSession(s.endpointResolver, s.logger.IsDebugEnabled())
+// Clear resets every cached client back to its zero value so the next GetX
+// call rebuilds it. It never touches the clients/stscache maps themselves
+// (their key set is fixed at NewSessionCache time), so it's safe to call
+// concurrently with any GetX or Refresh.
+func (s *sessionCache) Clear() {
+	for _, ptr := range s.stscache {
+		ptr.Store(nil)
 	}
 
-	for role := range s.stscache {
-		s.stscache[role] = createStsSession(s.session, role, s.stsRegion, s.fips, s.logger.IsDebugEnabled())
+	for _, cache := range s.clients {
+		cache.cloudwatch.Store(nil)
+		cache.tagging.Store(nil)
+		cache.services.Range(func(name, _ any) bool {
+			cache.services.Delete(name)
+			return true
+		})
 	}
+}
 
-	for role, regions := range s.clients {
-		for region := range regions {
-			// if the role is just used in static jobs, then we
-			// can skip creating other sessions and potentially running
-			// into permissions errors or taking up needless cycles
-			s.clients[role][region].cloudwatch = createCloudwatchSession(s.session, &region, role, s.fips, s.logger.IsDebugEnabled())
-			if s.clients[role][region].onlyStatic {
-				continue
-			}
+// Refresh (re)builds every client up front, e.g. to pay the cost of
+// credential/STS round trips once at startup rather than on a scrape's
+// critical path. It's just a bulk Store across the same atomic pointers and
+// sync.Map the GetX methods use lazily, so it's safe to call concurrently
+// with them too.
+func (s *sessionCache) Refresh(ctx context.Context) {
+	// the base config really only needs to be loaded once at runtime
+	s.baseConfig(ctx)
+
+	for role, ptr := range s.stscache {
+		ptr.Store(s.createStsSession(ctx, role, s.stsRegion))
+	}
+
+	for key, cache := range s.clients {
+		region := key.region
+		// if the role is just used in static jobs, then we
+		// can skip creating other sessions and potentially running
+		// into permissions errors or taking up needless cycles
+		cache.cloudwatch.Store(s.createCloudwatchSession(ctx, &region, key.role))
+		if cache.onlyStatic {
+			continue
+		}
 
-			s.clients[role][region].tagging = createTagSession(s.session, &region, role, s.logger.IsDebugEnabled())
-			s.clients[role][region].asg = createASGSession(s.session, &region, role, s.logger.IsDebugEnabled())
-			s.clients[role][region].ec2 = createEC2Session(s.session, &region, role, s.fips, s.logger.IsDebugEnabled())
-			s.clients[role][region].dms = createDMSSession(s.session, &region, role, s.fips, s.logger.IsDebugEnabled())
-			s.clients[role][region].apiGateway = createAPIGatewaySession(s.session, &region, role, s.fips, s.logger.IsDebugEnabled())
+		cache.tagging.Store(s.createTagSession(ctx, &region, key.role))
+		for _, name := range []string{"asg", "ec2", "dms", "apigateway", "logs", "iam"} {
+			cache.services.Store(name, serviceRegistry[name].factory(s, ctx, &region, key.role))
 		}
 	}
-
-	s.cleared = false
-	s.refreshed = true
 }
 
-func (s *sessionCache) GetSTS(role Role) stsiface.STSAPI {
-	// if we have not refreshed then we need to lock in case we are accessing concurrently
-	if !s.refreshed {
-		s.mu.Lock()
-		defer s.mu.Unlock()
+// GetSTS returns a cached STS client for role, building and caching one on
+// first use. A role that wasn't enumerated in config.Discovery.Jobs or
+// config.Static at NewSessionCache time has no entry in s.stscache; rather
+// than panic on the resulting nil pointer, such a role just gets an
+// uncached client built fresh on every call.
+func (s *sessionCache) GetSTS(ctx context.Context, role Role) *sts.Client {
+	ptr, ok := s.stscache[role]
+	if !ok {
+		return s.createStsSession(ctx, role, s.stsRegion)
 	}
-	if sess, ok := s.stscache[role]; ok && sess != nil {
-		return sess
+	if client := ptr.Load(); client != nil {
+		return client
 	}
-	s.stscache[role] = createStsSession(s.session, role, s.stsRegion, s.fips, s.logger.IsDebugEnabled())
-	return s.stscache[role]
-}
 
-func (s *sessionCache) GetCloudwatch(region *string, role Role) cloudwatchiface.CloudWatchAPI {
-	// if we have not refreshed then we need to lock in case we are accessing concurrently
-	if !s.refreshed {
-		s.mu.Lock()
-		defer s.mu.Unlock()
-	}
-	if sess, ok := s.clients[role][*region]; ok && sess.cloudwatch != nil {
-		return sess.cloudwatch
+	client := s.createStsSession(ctx, role, s.stsRegion)
+	if ptr.CompareAndSwap(nil, client) {
+		return client
 	}
-	s.clients[role][*region].cloudwatch = createCloudwatchSession(s.session, region, role, s.fips, s.logger.IsDebugEnabled())
-	return s.clients[role][*region].cloudwatch
+	return ptr.Load()
 }
 
-func (s *sessionCache) GetTagging(region *string, role Role) resourcegroupstaggingapiiface.ResourceGroupsTaggingAPIAPI {
-	// if we have not refreshed then we need to lock in case we are accessing concurrently
-	if !s.refreshed {
-		s.mu.Lock()
-		defer s.mu.Unlock()
+// GetCloudwatch returns a cached Cloudwatch client for (role, region),
+// building and caching one on first use. A (role, region) pair that wasn't
+// enumerated at NewSessionCache time has no entry in s.clients; rather than
+// panic on the resulting nil pointer, such a pair just gets an uncached
+// client built fresh on every call.
+func (s *sessionCache) GetCloudwatch(ctx context.Context, region *string, role Role) *cloudwatch.Client {
+	cache, ok := s.clients[clientKey{role, *region}]
+	if !ok {
+		return s.createCloudwatchSession(ctx, region, role)
 	}
-	if sess, ok := s.clients[role][*region]; ok && sess.tagging != nil {
-		return sess.tagging
+	if client := cache.cloudwatch.Load(); client != nil {
+		return client
 	}
 
-	s.clients[role][*region].tagging = createTagSession(s.session, region, role, s.fips)
-	return s.clients[role][*region].tagging
-}
-
-func (s *sessionCache) GetASG(region *string, role Role) autoscalingiface.AutoScalingAPI {
-	// if we have not refreshed then we need to lock in case we are accessing concurrently
-	if !s.refreshed {
-		s.mu.Lock()
-		defer s.mu.Unlock()
-	}
-	if sess, ok := s.clients[role][*region]; ok && sess.asg != nil {
-		return sess.asg
+	client := s.createCloudwatchSession(ctx, region, role)
+	if cache.cloudwatch.CompareAndSwap(nil, client) {
+		return client
 	}
-
-	s.clients[role][*region].asg = createASGSession(s.session, region, role, s.fips)
-	return s.clients[role][*region].asg
+	return cache.cloudwatch.Load()
 }
 
-func (s *sessionCache) GetEC2(region *string, role Role) ec2iface.EC2API {
-	// if we have not refreshed then we need to lock in case we are accessing concurrently
-	if !s.refreshed {
-		s.mu.Lock()
-		defer s.mu.Unlock()
+// GetTagging returns a cached resource-groups-tagging client for
+// (role, region), building and caching one on first use. A (role, region)
+// pair that wasn't enumerated at NewSessionCache time has no entry in
+// s.clients; rather than panic on the resulting nil pointer, such a pair
+// just gets an uncached client built fresh on every call.
+func (s *sessionCache) GetTagging(ctx context.Context, region *string, role Role) *resourcegroupstaggingapi.Client {
+	cache, ok := s.clients[clientKey{role, *region}]
+	if !ok {
+		return s.createTagSession(ctx, region, role)
 	}
-	if sess, ok := s.clients[role][*region]; ok && sess.ec2 != nil {
-		return sess.ec2
+	if client := cache.tagging.Load(); client != nil {
+		return client
 	}
 
-	s.clients[role][*region].ec2 = createEC2Session(s.session, region, role, s.fips, s.logger.IsDebugEnabled())
-	return s.clients[role][*region].ec2
-}
-
-func (s *sessionCache) GetDMS(region *string, role Role) databasemigrationserviceiface.DatabaseMigrationServiceAPI {
-	// if we have not refreshed then we need to lock in case we are accessing concurrently
-	if !s.refreshed {
-		s.mu.Lock()
-		defer s.mu.Unlock()
+	client := s.createTagSession(ctx, region, role)
+	if cache.tagging.CompareAndSwap(nil, client) {
+		return client
 	}
-	if sess, ok := s.clients[role][*region]; ok && sess.dms != nil {
-		return sess.dms
-	}
-
-	s.clients[role][*region].dms = createDMSSession(s.session, region, role, s.fips, s.logger.IsDebugEnabled())
-	return s.clients[role][*region].dms
+	return cache.tagging.Load()
 }
 
-func (s *sessionCache) GetAPIGateway(region *string, role Role) apigatewayiface.APIGatewayAPI {
-	// if we have not refreshed then we need to lock in case we are accessing concurrently
-	if !s.refreshed {
-		s.mu.Lock()
-		defer s.mu.Unlock()
-	}
-	if sess, ok := s.clients[role][*region]; ok && sess.apiGateway != nil {
-		return sess.apiGateway
-	}
+func (s *sessionCache) GetASG(ctx context.Context, region *string, role Role) *autoscaling.Client {
+	return s.GetService(ctx, "asg", region, role).(*autoscaling.Client)
+}
 
-	s.clients[role][*region].apiGateway = createAPIGatewaySession(s.session, region, role, s.fips, s.logger.IsDebugEnabled())
-	return s.clients[role][*region].apiGateway
+func (s *sessionCache) GetEC2(ctx context.Context, region *string, role Role) *ec2.Client {
+	return s.GetService(ctx, "ec2", region, role).(*ec2.Client)
+}
 
+func (s *sessionCache) GetDMS(ctx context.Context, region *string, role Role) *databasemigrationservice.Client {
+	return s.GetService(ctx, "dms", region, role).(*databasemigrationservice.Client)
 }
 
-func setExternalID(ID string) func(p *stscreds.AssumeRoleProvider) {
-	return func(p *stscreds.AssumeRoleProvider) {
-		if ID != "" {
-			p.ExternalID = aws.String(ID)
-		}
-	}
+func (s *sessionCache) GetAPIGateway(ctx context.Context, region *string, role Role) *apigateway.Client {
+	return s.GetService(ctx, "apigateway", region, role).(*apigateway.Client)
 }
 
-func setSTSCreds(sess *session.Session, config *aws.Config, role Role) *aws.Config {
-	if role.RoleArn != "" {
-		config.Credentials = stscreds.NewCredentials(
-			sess, role.RoleArn, setExternalID(role.ExternalID))
-	}
-	return config
+// MFATokenProvider supplies the token code for AssumeRole calls against a
+// role with an MFASerial set. It defaults to reading from stdin, which only
+// works when the exporter is run interactively and one-shot. The exporter is
+// normally an unattended process scraped on an interval with no TTY
+// attached, so deployments using MFA-protected roles must override this
+// (e.g. with a callback that reads a cached TOTP code or fetches one from a
+// hardware/virtual token) before calling NewSessionCache.
+var MFATokenProvider func() (string, error) = stscreds.StdinTokenProvider
+
+// errCredentialsProvider is an aws.CredentialsProvider that always fails
+// with err. It's used so a SourceProfile that can't be loaded surfaces as a
+// loud, actionable failure on every AWS call made under the role, rather
+// than a silent fallback to a different identity.
+type errCredentialsProvider struct {
+	err error
 }
 
-func getAwsRetryer() aws.RequestRetryer {
-	return client.DefaultRetryer{
-		NumMaxRetries: 5,
-		// MaxThrottleDelay and MinThrottleDelay used for throttle errors
-		MaxThrottleDelay: 10 * time.Second,
-		MinThrottleDelay: 1 * time.Second,
-		// For other errors
-		MaxRetryDelay: 3 * time.Second,
-		MinRetryDelay: 1 * time.Second,
-	}
+func (p *errCredentialsProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	return aws.Credentials{}, p.err
 }
 
-func createAWSSession(resolver endpoints.ResolverFunc, isDebugEnabled bool) *session.Session {
+// samlAssumeRoleProvider is an aws.CredentialsProvider for roles assumed via
+// AssumeRoleWithSAML. The v2 SDK, unlike v1, doesn't ship a stscreds
+// provider for this flow, so it's implemented directly against sts.Client.
+type samlAssumeRoleProvider struct {
+	client          *sts.Client
+	roleArn         string
+	principalArn    string
+	samlAssertion   string
+	durationSeconds int32
+}
 
-	config := aws.Config{
-		CredentialsChainVerboseErrors: aws.Bool(true),
-		EndpointResolver:              resolver,
+func (p *samlAssumeRoleProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	input := &sts.AssumeRoleWithSAMLInput{
+		RoleArn:       aws.String(p.roleArn),
+		PrincipalArn:  aws.String(p.principalArn),
+		SAMLAssertion: aws.String(p.samlAssertion),
+	}
+	if p.durationSeconds > 0 {
+		input.DurationSeconds = aws.Int32(p.durationSeconds)
 	}
 
-	if isDebugEnabled {
-		config.LogLevel = aws.LogLevel(aws.LogDebugWithHTTPBody)
+	out, err := p.client.AssumeRoleWithSAML(ctx, input)
+	if err != nil {
+		return aws.Credentials{}, fmt.Errorf("assume role with SAML for %s: %w", p.roleArn, err)
 	}
 
-	sess := session.Must(session.NewSessionWithOptions(session.Options{
-		SharedConfigState: session.SharedConfigEnable,
-		Config:            config,
-	}))
-	return sess
+	return aws.Credentials{
+		AccessKeyID:     aws.ToString(out.Credentials.AccessKeyId),
+		SecretAccessKey: aws.ToString(out.Credentials.SecretAccessKey),
+		SessionToken:    aws.ToString(out.Credentials.SessionToken),
+		CanExpire:       true,
+		Expires:         aws.ToTime(out.Credentials.Expiration),
+		Source:          "SAMLAssumeRoleProvider",
+	}, nil
 }
 
-func createStsSession(sess *session.Session, role Role, region string, fips bool, isDebugEnabled bool) *sts.STS {
-	maxStsRetries := 5
-	config := &aws.Config{MaxRetries: &maxStsRetries}
-
-	if region != "" {
-		config = config.WithRegion(region).WithSTSRegionalEndpoint(endpoints.RegionalSTSEndpoint)
-	}
-
-	if fips {
-		// https://aws.amazon.com/compliance/fips/
-		endpoint := fmt.Sprintf("https://sts-fips.%s.amazonaws.com", region)
-		config.Endpoint = aws.String(endpoint)
+// credentialsFor resolves the credential provider chain for a role, picking
+// the first strategy that applies:
+//
+//  1. a web identity token file (IRSA on EKS, or any OIDC-federated role)
+//  2. AssumeRoleWithSAML, for roles federated through a SAML identity provider
+//  3. a classic AssumeRole, optionally hardened with an MFA serial/token-code
+//     callback (see MFATokenProvider)
+//  4. the base config's default chain, which already covers static
+//     credentials, an EC2 instance profile, and an ECS task role endpoint
+//
+// role.SourceProfile, when set, loads the base credentials from a named
+// profile in the shared config/credentials files instead of the process-wide
+// default chain, so a single exporter can hold several distinct identities.
+// The profile is loaded with the same endpoint resolver and debug logging as
+// the base config, so SourceProfile composes with per-service endpoint
+// overrides (see endpoints.go) instead of silently bypassing them; a profile
+// that fails to load fails loudly, via an errCredentialsProvider, so every
+// subsequent AWS call for this role errors out instead of silently scraping
+// under a different (and possibly wrong) identity.
+func (s *sessionCache) credentialsFor(ctx context.Context, role Role) aws.CredentialsProvider {
+	base := s.baseConfig(ctx)
+	if role.SourceProfile != "" {
+		profileConfig, err := loadAWSConfig(ctx, s.endpointResolver, s.logger.IsDebugEnabled(), config.WithSharedConfigProfile(role.SourceProfile))
+		if err != nil {
+			s.logger.Error(err, "failed to load source profile", "profile", role.SourceProfile)
+			return &errCredentialsProvider{err: fmt.Errorf("load source profile %q: %w", role.SourceProfile, err)}
+		}
+		base = profileConfig
 	}
 
-	if isDebugEnabled {
-		config.LogLevel = aws.LogLevel(aws.LogDebugWithHTTPBody)
+	switch credentialStrategyFor(role) {
+	case credentialStrategyWebIdentity:
+		stsClient := sts.NewFromConfig(base)
+		return aws.NewCredentialsCache(stscreds.NewWebIdentityRoleProvider(
+			stsClient, role.RoleArn, stscreds.IdentityTokenFile(role.WebIdentityTokenFile),
+			func(o *stscreds.WebIdentityRoleOptions) {
+				if role.SessionName != "" {
+					o.RoleSessionName = role.SessionName
+				}
+			},
+		))
+	case credentialStrategySAML:
+		stsClient := sts.NewFromConfig(base)
+		return aws.NewCredentialsCache(&samlAssumeRoleProvider{
+			client:          stsClient,
+			roleArn:         role.RoleArn,
+			principalArn:    role.PrincipalArn,
+			samlAssertion:   role.SAMLAssertion,
+			durationSeconds: int32(role.DurationSeconds),
+		})
+	case credentialStrategyAssumeRole:
+		stsClient := sts.NewFromConfig(base)
+		return aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(stsClient, role.RoleArn, func(o *stscreds.AssumeRoleOptions) {
+			if role.ExternalID != "" {
+				o.ExternalID = aws.String(role.ExternalID)
+			}
+			if role.SessionName != "" {
+				o.RoleSessionName = role.SessionName
+			}
+			if role.DurationSeconds > 0 {
+				o.Duration = time.Duration(role.DurationSeconds) * time.Second
+			}
+			if role.MFASerial != "" {
+				o.SerialNumber = aws.String(role.MFASerial)
+				o.TokenProvider = MFATokenProvider
+			}
+		}))
+	default:
+		return base.Credentials
 	}
-
-	return sts.New(sess, setSTSCreds(sess, config, role))
 }
 
-func createCloudwatchSession(sess *session.Session, region *string, role Role, fips bool, isDebugEnabled bool) *cloudwatch.CloudWatch {
+// credentialStrategy names the branches credentialStrategyFor can pick.
+type credentialStrategy int
 
-	config := &aws.Config{Region: region, Retryer: getAwsRetryer()}
+const (
+	credentialStrategyDefault credentialStrategy = iota
+	credentialStrategyWebIdentity
+	credentialStrategySAML
+	credentialStrategyAssumeRole
+)
 
-	if fips {
-		// https://docs.aws.amazon.com/general/latest/gr/cw_region.html
-		endpoint := fmt.Sprintf("https://monitoring-fips.%s.amazonaws.com", *region)
-		config.Endpoint = aws.String(endpoint)
+// credentialStrategyFor picks the credential strategy credentialsFor should
+// use for role, in priority order: web identity, then SAML, then classic
+// AssumeRole, then the base config's default chain. It's a pure function of
+// role so the selection logic can be table-tested without constructing a
+// sessionCache or talking to STS.
+func credentialStrategyFor(role Role) credentialStrategy {
+	switch {
+	case role.WebIdentityTokenFile != "":
+		return credentialStrategyWebIdentity
+	case role.SAMLAssertion != "":
+		return credentialStrategySAML
+	case role.RoleArn != "":
+		return credentialStrategyAssumeRole
+	default:
+		return credentialStrategyDefault
 	}
+}
+
+// loadAWSConfig loads an aws.Config with the exporter's standard endpoint
+// resolver and debug logging applied, plus any caller-supplied options (e.g.
+// a shared config profile).
+func loadAWSConfig(ctx context.Context, resolver aws.EndpointResolverWithOptions, isDebugEnabled bool, extra ...func(*config.LoadOptions) error) (aws.Config, error) {
+	opts := append([]func(*config.LoadOptions) error{
+		config.WithEndpointResolverWithOptions(resolver),
+	}, extra...)
 
 	if isDebugEnabled {
-		config.LogLevel = aws.LogLevel(aws.LogDebugWithHTTPBody)
+		opts = append(opts, config.WithClientLogMode(aws.LogRequestWithBody|aws.LogResponseWithBody))
 	}
 
-	return cloudwatch.New(sess, setSTSCreds(sess, config, role))
+	return config.LoadDefaultConfig(ctx, opts...)
 }
 
-func createTagSession(sess *session.Session, region *string, role Role, isDebugEnabled bool) *r.ResourceGroupsTaggingAPI {
-	maxResourceGroupTaggingRetries := 5
-	config := &aws.Config{
-		Region:                        region,
-		MaxRetries:                    &maxResourceGroupTaggingRetries,
-		CredentialsChainVerboseErrors: aws.Bool(true),
+func createAWSConfig(ctx context.Context, resolver aws.EndpointResolverWithOptions, isDebugEnabled bool) aws.Config {
+	cfg, err := loadAWSConfig(ctx, resolver, isDebugEnabled)
+	if err != nil {
+		// mirrors the session.Must behavior of the v1 SDK: a config that
+		// cannot be loaded at startup is unrecoverable.
+		panic(fmt.Sprintf("failed to load default AWS config: %v", err))
 	}
+	return cfg
+}
 
-	if isDebugEnabled {
-		config.LogLevel = aws.LogLevel(aws.LogDebugWithHTTPBody)
+// baseConfig returns the process-wide default aws.Config, loading it at most
+// once regardless of how many goroutines call in concurrently: readers race
+// a CompareAndSwap on the same atomic.Pointer the rest of the cache uses for
+// its clients, rather than the unsynchronized bool/struct pair this used to
+// be, so it's safe to call from Refresh and every GetX/create*Session path
+// at once.
+func (s *sessionCache) baseConfig(ctx context.Context) aws.Config {
+	if cfg := s.config.Load(); cfg != nil {
+		return *cfg
 	}
 
-	return r.New(sess, setSTSCreds(sess, config, role))
+	cfg := createAWSConfig(ctx, s.endpointResolver, s.logger.IsDebugEnabled())
+	s.config.CompareAndSwap(nil, &cfg)
+	return *s.config.Load()
 }
 
-func createASGSession(sess *session.Session, region *string, role Role, isDebugEnabled bool) autoscalingiface.AutoScalingAPI {
-	maxAutoScalingAPIRetries := 5
-	config := &aws.Config{Region: region, MaxRetries: &maxAutoScalingAPIRetries}
+// commonClientOptions gathers the handful of settings every AWS service
+// client needs (credentials, retryer, rate-limited/instrumented HTTP client,
+// region, FIPS, debug logging) so each createXSession helper, and every
+// serviceRegistry factory, only has to map them onto its own Options type.
+type commonClientOptions struct {
+	region      string
+	credentials aws.CredentialsProvider
+	retryer     aws.Retryer
+	httpClient  *http.Client
+	fips        bool
+	debug       bool
+}
 
-	if isDebugEnabled {
-		config.LogLevel = aws.LogLevel(aws.LogDebugWithHTTPBody)
+func (s *sessionCache) commonClientOptions(ctx context.Context, service, region string, role Role) commonClientOptions {
+	return commonClientOptions{
+		region:      region,
+		credentials: s.credentialsFor(ctx, role),
+		retryer:     s.retryerFor(service, region)(),
+		httpClient:  s.httpClientFor(service, region, role),
+		fips:        s.fips,
+		debug:       s.logger.IsDebugEnabled(),
 	}
-
-	return autoscaling.New(sess, setSTSCreds(sess, config, role))
 }
 
-func createEC2Session(sess *session.Session, region *string, role Role, fips bool, isDebugEnabled bool) ec2iface.EC2API {
-	maxEC2APIRetries := 10
-	config := &aws.Config{Region: region, MaxRetries: &maxEC2APIRetries}
-	if fips {
-		// https://docs.aws.amazon.com/general/latest/gr/ec2-service.html
-		endpoint := fmt.Sprintf("https://ec2-fips.%s.amazonaws.com", *region)
-		config.Endpoint = aws.String(endpoint)
-	}
+func (s *sessionCache) createStsSession(ctx context.Context, role Role, region string) *sts.Client {
+	common := s.commonClientOptions(ctx, "sts", region, role)
+	return sts.NewFromConfig(s.baseConfig(ctx), func(o *sts.Options) {
+		o.Credentials = common.credentials
+		o.Retryer = common.retryer
+		o.HTTPClient = common.httpClient
 
-	if isDebugEnabled {
-		config.LogLevel = aws.LogLevel(aws.LogDebugWithHTTPBody)
-	}
+		if common.region != "" {
+			o.Region = common.region
+		}
 
-	return ec2.New(sess, setSTSCreds(sess, config, role))
-}
+		if common.fips {
+			// https://aws.amazon.com/compliance/fips/
+			o.EndpointOptions.UseFIPSEndpoint = aws.FIPSEndpointStateEnabled
+		}
 
-func createDMSSession(sess *session.Session, region *string, role Role, fips bool, isDebugEnabled bool) databasemigrationserviceiface.DatabaseMigrationServiceAPI {
-	maxDMSAPIRetries := 5
-	config := &aws.Config{Region: region, MaxRetries: &maxDMSAPIRetries}
-	if fips {
-		// https://docs.aws.amazon.com/general/latest/gr/dms.html
-		endpoint := fmt.Sprintf("https://dms-fips.%s.amazonaws.com", *region)
-		config.Endpoint = aws.String(endpoint)
-	}
+		if common.debug {
+			o.ClientLogMode = aws.LogRequestWithBody | aws.LogResponseWithBody
+		}
+	})
+}
 
-	if isDebugEnabled {
-		config.LogLevel = aws.LogLevel(aws.LogDebugWithHTTPBody)
-	}
+func (s *sessionCache) createCloudwatchSession(ctx context.Context, region *string, role Role) *cloudwatch.Client {
+	common := s.commonClientOptions(ctx, "cloudwatch", *region, role)
+	return cloudwatch.NewFromConfig(s.baseConfig(ctx), func(o *cloudwatch.Options) {
+		o.Region = common.region
+		o.Credentials = common.credentials
+		o.Retryer = common.retryer
+		o.HTTPClient = common.httpClient
+
+		if common.fips {
+			// https://docs.aws.amazon.com/general/latest/gr/cw_region.html
+			o.EndpointOptions.UseFIPSEndpoint = aws.FIPSEndpointStateEnabled
+		}
 
-	return databasemigrationservice.New(sess, setSTSCreds(sess, config, role))
+		if common.debug {
+			o.ClientLogMode = aws.LogRequestWithBody | aws.LogResponseWithBody
+		}
+	})
 }
 
-func createAPIGatewaySession(sess *session.Session, region *string, role Role, fips bool, isDebugEnabled bool) apigatewayiface.APIGatewayAPI {
-	maxAPIGatewayAPIRetries := 5
-	config := &aws.Config{Region: region, MaxRetries: &maxAPIGatewayAPIRetries}
-	if fips {
-		// https://docs.aws.amazon.com/general/latest/gr/apigateway.html
-		endpoint := fmt.Sprintf("https://apigateway-fips.%s.amazonaws.com", *region)
-		config.Endpoint = aws.String(endpoint)
-	}
+func (s *sessionCache) createTagSession(ctx context.Context, region *string, role Role) *resourcegroupstaggingapi.Client {
+	common := s.commonClientOptions(ctx, "tagging", *region, role)
+	return resourcegroupstaggingapi.NewFromConfig(s.baseConfig(ctx), func(o *resourcegroupstaggingapi.Options) {
+		o.Region = common.region
+		o.Credentials = common.credentials
+		o.Retryer = common.retryer
+		o.HTTPClient = common.httpClient
 
-	if isDebugEnabled {
-		config.LogLevel = aws.LogLevel(aws.LogDebugWithHTTPBody)
-	}
-
-	return apigateway.New(sess, setSTSCreds(sess, config, role))
+		if common.debug {
+			o.ClientLogMode = aws.LogRequestWithBody | aws.LogResponseWithBody
+		}
+	})
 }
+
@@ -0,0 +1,56 @@
+package exporter
+
+import "testing"
+
+func TestCredentialStrategyFor(t *testing.T) {
+	tests := []struct {
+		name string
+		role Role
+		want credentialStrategy
+	}{
+		{
+			name: "web identity token file wins over everything else",
+			role: Role{
+				RoleArn:              "arn:aws:iam::123456789012:role/web-identity",
+				WebIdentityTokenFile: "/var/run/secrets/token",
+				SAMLAssertion:        "base64-saml-assertion",
+			},
+			want: credentialStrategyWebIdentity,
+		},
+		{
+			name: "SAML assertion wins over a plain role ARN",
+			role: Role{
+				RoleArn:       "arn:aws:iam::123456789012:role/saml",
+				SAMLAssertion: "base64-saml-assertion",
+			},
+			want: credentialStrategySAML,
+		},
+		{
+			name: "role ARN alone selects classic AssumeRole",
+			role: Role{
+				RoleArn: "arn:aws:iam::123456789012:role/classic",
+			},
+			want: credentialStrategyAssumeRole,
+		},
+		{
+			name: "no role ARN falls back to the default credential chain",
+			role: Role{},
+			want: credentialStrategyDefault,
+		},
+		{
+			name: "source profile alone doesn't select AssumeRole on its own",
+			role: Role{
+				SourceProfile: "some-profile",
+			},
+			want: credentialStrategyDefault,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := credentialStrategyFor(tt.role); got != tt.want {
+				t.Errorf("credentialStrategyFor(%+v) = %v, want %v", tt.role, got, tt.want)
+			}
+		})
+	}
+}
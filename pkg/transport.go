@@ -0,0 +1,280 @@
+package exporter
+
+import (
+	"context"
+	"crypto/tls"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+)
+
+// HTTPClientConfig tunes the shared *http.Transport every AWS service client
+// is built on top of, and the token-bucket rate limit applied per
+// (service, region, role). It is surfaced on ScrapeConf as the `http_client`
+// block so operators can keep YACE under a service's GetMetricData TPS quota
+// without patching code.
+type HTTPClientConfig struct {
+	MaxIdleConns          int           `yaml:"max_idle_conns"`
+	MaxIdleConnsPerHost   int           `yaml:"max_idle_conns_per_host"`
+	IdleConnTimeout       time.Duration `yaml:"idle_conn_timeout"`
+	TLSInsecureSkipVerify bool          `yaml:"tls_insecure_skip_verify"`
+	RateLimitPerSecond    float64       `yaml:"rate_limit_per_second"`
+	RateLimitBurst        int           `yaml:"rate_limit_burst"`
+	RetryMaxAttempts      int           `yaml:"retry_max_attempts"`
+	// MinRetryDelay/MaxRetryDelay bound the backoff for ordinary retryable
+	// errors; MinThrottleDelay/MaxThrottleDelay bound it for throttling
+	// errors specifically, which usually warrant backing off harder. These
+	// replace the v1 SDK's client.DefaultRetryer fields of the same name.
+	MinRetryDelay    time.Duration `yaml:"min_retry_delay"`
+	MaxRetryDelay    time.Duration `yaml:"max_retry_delay"`
+	MinThrottleDelay time.Duration `yaml:"min_throttle_delay"`
+	MaxThrottleDelay time.Duration `yaml:"max_throttle_delay"`
+}
+
+func (c HTTPClientConfig) withDefaults() HTTPClientConfig {
+	if c.MaxIdleConns == 0 {
+		c.MaxIdleConns = 100
+	}
+	if c.MaxIdleConnsPerHost == 0 {
+		c.MaxIdleConnsPerHost = 10
+	}
+	if c.IdleConnTimeout == 0 {
+		c.IdleConnTimeout = 90 * time.Second
+	}
+	if c.RateLimitPerSecond == 0 {
+		c.RateLimitPerSecond = 20
+	}
+	if c.RateLimitBurst == 0 {
+		c.RateLimitBurst = 20
+	}
+	if c.RetryMaxAttempts == 0 {
+		c.RetryMaxAttempts = 5
+	}
+	// defaults match the old client.DefaultRetryer's MinRetryDelay/
+	// MaxRetryDelay/MinThrottleDelay/MaxThrottleDelay values.
+	if c.MinRetryDelay == 0 {
+		c.MinRetryDelay = 1 * time.Second
+	}
+	if c.MaxRetryDelay == 0 {
+		c.MaxRetryDelay = 3 * time.Second
+	}
+	if c.MinThrottleDelay == 0 {
+		c.MinThrottleDelay = 1 * time.Second
+	}
+	if c.MaxThrottleDelay == 0 {
+		c.MaxThrottleDelay = 10 * time.Second
+	}
+	return c
+}
+
+func newHTTPTransport(cfg HTTPClientConfig) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = cfg.MaxIdleConns
+	transport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	transport.IdleConnTimeout = cfg.IdleConnTimeout
+
+	if cfg.TLSInsecureSkipVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	return transport
+}
+
+// awsMetrics are the Prometheus series emitted for every AWS API call YACE
+// makes while scraping, labeled by service/region/operation so a latency
+// regression or a burst of throttles can be traced back to a single
+// discovery or static job.
+type awsMetrics struct {
+	requestDuration *prometheus.HistogramVec
+	requestsTotal   *prometheus.CounterVec
+	retriesTotal    *prometheus.CounterVec
+	throttlesTotal  *prometheus.CounterVec
+}
+
+func newAWSMetrics() *awsMetrics {
+	baseLabels := []string{"service", "region", "operation"}
+	return &awsMetrics{
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "yace",
+			Subsystem: "aws",
+			Name:      "request_duration_seconds",
+			Help:      "Duration in seconds of AWS API requests made by the exporter.",
+			Buckets:   prometheus.DefBuckets,
+		}, baseLabels),
+		requestsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "yace",
+			Subsystem: "aws",
+			Name:      "requests_total",
+			Help:      "Total number of AWS API requests made by the exporter, by response status.",
+		}, append(baseLabels, "status")),
+		retriesTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "yace",
+			Subsystem: "aws",
+			Name:      "request_retries_total",
+			Help:      "Total number of AWS API request retries.",
+		}, baseLabels),
+		throttlesTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "yace",
+			Subsystem: "aws",
+			Name:      "request_throttles_total",
+			Help:      "Total number of AWS API requests throttled by the service.",
+		}, baseLabels),
+	}
+}
+
+func (m *awsMetrics) MustRegister(reg prometheus.Registerer) {
+	reg.MustRegister(m.requestDuration, m.requestsTotal, m.retriesTotal, m.throttlesTotal)
+}
+
+// limiterKey identifies one token-bucket limiter. Keeping role in the key
+// (rather than just role.RoleArn) means two roles that happen to share an
+// ARN but differ in, say, ExternalID still get independent buckets, matching
+// how the rest of the cache keys on Role.
+type limiterKey struct {
+	service string
+	region  string
+	role    Role
+}
+
+// limiterRegistry hands out one rate.Limiter per (service, region, role), so
+// a chatty role in one region can't burn through another role's share of a
+// service's TPS quota.
+type limiterRegistry struct {
+	mu       sync.Mutex
+	limiters map[limiterKey]*rate.Limiter
+	rps      rate.Limit
+	burst    int
+}
+
+func newLimiterRegistry(rps float64, burst int) *limiterRegistry {
+	return &limiterRegistry{
+		limiters: map[limiterKey]*rate.Limiter{},
+		rps:      rate.Limit(rps),
+		burst:    burst,
+	}
+}
+
+func (r *limiterRegistry) limiterFor(service, region string, role Role) *rate.Limiter {
+	key := limiterKey{service: service, region: region, role: role}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if l, ok := r.limiters[key]; ok {
+		return l
+	}
+	l := rate.NewLimiter(r.rps, r.burst)
+	r.limiters[key] = l
+	return l
+}
+
+// instrumentedTransport enforces the per-(service, region, role) rate limit
+// and records latency/status metrics for every HTTP round trip an AWS
+// service client makes.
+type instrumentedTransport struct {
+	next    http.RoundTripper
+	limiter *rate.Limiter
+	service string
+	region  string
+	metrics *awsMetrics
+}
+
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+
+	operation := awsmiddleware.GetOperationName(req.Context())
+	if operation == "" {
+		operation = "unknown"
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	t.metrics.requestDuration.WithLabelValues(t.service, t.region, operation).Observe(time.Since(start).Seconds())
+
+	status := "error"
+	if resp != nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	t.metrics.requestsTotal.WithLabelValues(t.service, t.region, operation, status).Inc()
+
+	return resp, err
+}
+
+// instrumentedRetryer wraps the standard retryer to count retries and, among
+// those, the subset caused by service-side throttling.
+type instrumentedRetryer struct {
+	*retry.Standard
+	service string
+	region  string
+	metrics *awsMetrics
+}
+
+var throttleClassifier = retry.RetryableErrorCode{Codes: retry.DefaultThrottleErrorCodes}
+
+func (r *instrumentedRetryer) GetRetryToken(ctx context.Context, opErr error) (func(error) error, error) {
+	release, err := r.Standard.GetRetryToken(ctx, opErr)
+	if err == nil {
+		operation := awsmiddleware.GetOperationName(ctx)
+		if operation == "" {
+			operation = "unknown"
+		}
+		r.metrics.retriesTotal.WithLabelValues(r.service, r.region, operation).Inc()
+		if throttleClassifier.IsErrorRetryable(opErr) == aws.TrueTernary {
+			r.metrics.throttlesTotal.WithLabelValues(r.service, r.region, operation).Inc()
+		}
+	}
+	return release, err
+}
+
+// configurableBackoff reproduces the v1 SDK's client.DefaultRetryer backoff
+// model on top of v2's retry.BackoffDelayer: a delay that doubles with each
+// attempt up to a max, drawn from a throttle-specific [min,max] range when
+// the error is a service-side throttle and a plain-retry range otherwise.
+type configurableBackoff struct {
+	minRetryDelay    time.Duration
+	maxRetryDelay    time.Duration
+	minThrottleDelay time.Duration
+	maxThrottleDelay time.Duration
+}
+
+func (b *configurableBackoff) BackoffDelay(attempt int, err error) (time.Duration, error) {
+	minDelay, maxDelay := b.minRetryDelay, b.maxRetryDelay
+	if throttleClassifier.IsErrorRetryable(err) == aws.TrueTernary {
+		minDelay, maxDelay = b.minThrottleDelay, b.maxThrottleDelay
+	}
+
+	delay := minDelay << attempt
+	if delay <= 0 || delay > maxDelay {
+		delay = maxDelay
+	}
+
+	return time.Duration(rand.Int63n(int64(delay) + 1)), nil
+}
+
+func newRetryer(service, region string, cfg HTTPClientConfig, metrics *awsMetrics) func() aws.Retryer {
+	return func() aws.Retryer {
+		return &instrumentedRetryer{
+			Standard: retry.NewStandard(func(o *retry.StandardOptions) {
+				o.MaxAttempts = cfg.RetryMaxAttempts
+				o.Backoff = &configurableBackoff{
+					minRetryDelay:    cfg.MinRetryDelay,
+					maxRetryDelay:    cfg.MaxRetryDelay,
+					minThrottleDelay: cfg.MinThrottleDelay,
+					maxThrottleDelay: cfg.MaxThrottleDelay,
+				}
+			}),
+			service: service,
+			region:  region,
+			metrics: metrics,
+		}
+	}
+}